@@ -17,38 +17,88 @@ limitations under the License.
 package canaryconfigmgr
 
 import (
+	"fmt"
 	"log"
+	"sync"
 
-	k8sCache "k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	k8sCache "k8s.io/client-go/tools/cache"
+
+	"golang.org/x/net/context"
 
 	"github.com/fission/fission/crd"
-	"time"
 	"k8s.io/apimachinery/pkg/fields"
+	"time"
 )
 
+// availabilityCheckTimeout bounds each individual CheckAvailability call made
+// against the metric provider.
+const availabilityCheckTimeout = 10 * time.Second
+
 type canaryConfigMgr struct {
-	fissionClient     *crd.FissionClient
-	kubeClient        *kubernetes.Clientset
-	canaryConfigStore         k8sCache.Store
-	canaryConfigController    k8sCache.Controller
-	requestTracker *RequestTracker // this is only for local testing.
-	promClient *PrometheusClient
-	crdClient         *rest.RESTClient
+	fissionClient          *crd.FissionClient
+	kubeClient             *kubernetes.Clientset
+	canaryConfigStore      k8sCache.Store
+	canaryConfigController k8sCache.Controller
+	requestTracker         *RequestTracker // this is only for local testing.
+	revisionTracker        *revisionTracker
+	metricProvider         MetricProvider
+	notifier               Notifier
+	crdClient              *rest.RESTClient
+
+	rootCtx    context.Context
+	rootCancel context.CancelFunc
+
+	cancelFuncsMutex *sync.Mutex
+	cancelFuncs      map[types.NamespacedName]context.CancelFunc
 }
 
-func MakeCanaryConfigMgr(fissionClient *crd.FissionClient, kubeClient *kubernetes.Clientset, crdClient *rest.RESTClient) (*canaryConfigMgr) {
-	// TODO : Use api end point of prometheus to verify it's target discovery is up even before we start this controller.
-	// GET /api/v1/status/config
+// MakeCanaryConfigMgr creates a canaryConfigMgr. If notifier is nil, rollout
+// events are silently discarded ; pass the result of NotifierFromFlags with
+// the controller's --slack-webhook-url / --slack-channel flag values to get
+// Slack notifications, or a test double to assert on them. version is recorded on
+// fission_canary_info ; pass the controller's own build version. The caller
+// is still responsible for mounting MetricsHandler() at /metrics on the
+// controller's HTTP server, since canaryConfigMgr doesn't own that server.
+//
+// ctx bounds the wait for the metrics backend to become available at
+// startup ; if ctx is cancelled or its deadline passes before the backend
+// responds, MakeCanaryConfigMgr returns ctx.Err() instead of blocking the
+// controller's startup forever.
+func MakeCanaryConfigMgr(ctx context.Context, fissionClient *crd.FissionClient, kubeClient *kubernetes.Clientset, crdClient *rest.RESTClient, prometheusSvc string, notifier Notifier, version string) (*canaryConfigMgr, error) {
+	rootCtx, rootCancel := context.WithCancel(ctx)
+
+	if notifier == nil {
+		notifier = noopNotifier{}
+	}
+
+	RecordBuildInfo(version)
 
 	configMgr := &canaryConfigMgr{
-		fissionClient: fissionClient,
-		kubeClient: kubeClient,
-		crdClient: crdClient,
-		requestTracker: makeRequestTracker(),
+		fissionClient:    fissionClient,
+		kubeClient:       kubeClient,
+		crdClient:        crdClient,
+		requestTracker:   makeRequestTracker(),
+		revisionTracker:  makeRevisionTracker(),
+		metricProvider:   makePrometheusClient(prometheusSvc),
+		notifier:         notifier,
+		rootCtx:          rootCtx,
+		rootCancel:       rootCancel,
+		cancelFuncsMutex: &sync.Mutex{},
+		cancelFuncs:      make(map[types.NamespacedName]context.CancelFunc),
+	}
 
+	// refuse to start the informer loop until the metrics backend is reachable ;
+	// otherwise every rollout would start out trusting empty query results as "no failures".
+	err := configMgr.waitForMetricProviderAvailability(rootCtx)
+	if err != nil {
+		rootCancel()
+		return nil, err
 	}
 
 	store, controller := configMgr.initCanaryConfigController()
@@ -58,60 +108,318 @@ func MakeCanaryConfigMgr(fissionClient *crd.FissionClient, kubeClient *kubernete
 	// TODO : Also start a go routine on startup to restart processing all canaryConfigs in the event of router restart
 	// in the middle of incrementing weights of funcN and decrementing funcN-1
 
-	return configMgr
+	go controller.Run(rootCtx.Done())
+
+	return configMgr, nil
+}
+
+// Stop cancels every in-flight canary rollout and unwinds the informer
+// loop cleanly, for use on controller shutdown.
+func (canaryCfgMgr *canaryConfigMgr) Stop() {
+	canaryCfgMgr.rootCancel()
 }
 
-func(canaryCfgMgr *canaryConfigMgr) initCanaryConfigController() (k8sCache.Store, k8sCache.Controller) {
+// waitForMetricProviderAvailability blocks, retrying with exponential
+// backoff, until the metric provider reports itself available or ctx is
+// done, whichever happens first.
+func (canaryCfgMgr *canaryConfigMgr) waitForMetricProviderAvailability(ctx context.Context) error {
+	backoff := 1 * time.Second
+	maxBackoff := 1 * time.Minute
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, availabilityCheckTimeout)
+		err := canaryCfgMgr.metricProvider.CheckAvailability(checkCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("Metrics backend not yet available, retrying in %v : %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (canaryCfgMgr *canaryConfigMgr) initCanaryConfigController() (k8sCache.Store, k8sCache.Controller) {
 	resyncPeriod := 30 * time.Second
 	listWatch := k8sCache.NewListWatchFromClient(canaryCfgMgr.crdClient, "canaryconfigs", metav1.NamespaceAll, fields.Everything())
 	store, controller := k8sCache.NewInformer(listWatch, &crd.CanaryConfig{}, resyncPeriod,
 		k8sCache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				canaryConfig := obj.(*crd.CanaryConfig)
-				go canaryCfgMgr.addCanaryConfig(canaryConfig)
+				canaryCfgMgr.updateCanaryTotalMetrics()
+				go canaryCfgMgr.addCanaryConfigWhenAvailable(canaryConfig)
 			},
 			DeleteFunc: func(obj interface{}) {
 				canaryConfig := obj.(*crd.CanaryConfig)
-				// TODO : Once a go routine is spawned inside `addCanaryConfig` function with `add` event, it's impossible
-				// to get the context of that go-routine when a `delete` event is received for the same canaryConfig.
-				// need to find a better way to kill those go routines
+				canaryCfgMgr.updateCanaryTotalMetrics()
 				go canaryCfgMgr.deleteCanaryConfig(canaryConfig)
 			},
 			UpdateFunc: func(oldObj interface{}, newObj interface{}) {
-				oldConfig := oldObj.(*crd.HTTPTrigger)
-				newConfig := newObj.(*crd.HTTPTrigger)
+				oldConfig := oldObj.(*crd.CanaryConfig)
+				newConfig := newObj.(*crd.CanaryConfig)
 				go canaryCfgMgr.updateCanaryConfig(oldConfig, newConfig)
-
 			},
 		})
 	return store, controller
 }
 
-func(canaryCfgMgr *canaryConfigMgr) addCanaryConfig(canaryConfig *crd.CanaryConfig) {
-	ticker := time.NewTicker(canaryConfig.Spec.WeightIncrementDuration)
-	quit := make(chan struct{})
+// startRollout registers a cancel func for key, cancelling and discarding
+// any rollout already registered for it first, and returns a context the new
+// rollout's analysis loop should run under.
+func (canaryCfgMgr *canaryConfigMgr) startRollout(key types.NamespacedName) context.Context {
+	ctx, cancel := context.WithCancel(canaryCfgMgr.rootCtx)
+
+	canaryCfgMgr.cancelFuncsMutex.Lock()
+	if oldCancel, ok := canaryCfgMgr.cancelFuncs[key]; ok {
+		oldCancel()
+	}
+	canaryCfgMgr.cancelFuncs[key] = cancel
+	canaryCfgMgr.cancelFuncsMutex.Unlock()
+
+	return ctx
+}
+
+// stopRollout cancels and forgets the rollout tracked for canaryConfig,
+// along with any revision-tracking state and per-trigger metric series
+// recorded for it.
+func (canaryCfgMgr *canaryConfigMgr) stopRollout(canaryConfig *crd.CanaryConfig) {
+	key := namespacedNameForCanaryConfig(canaryConfig)
+
+	canaryCfgMgr.cancelFuncsMutex.Lock()
+	cancel, ok := canaryCfgMgr.cancelFuncs[key]
+	delete(canaryCfgMgr.cancelFuncs, key)
+	canaryCfgMgr.cancelFuncsMutex.Unlock()
+
+	if ok {
+		cancel()
+	}
+	canaryCfgMgr.revisionTracker.delete(key)
+	deleteCanaryMetrics(canaryConfig.Metadata.Namespace, canaryConfig.Spec.Trigger.Name,
+		canaryConfig.Spec.FunctionN, canaryConfig.Spec.FunctionNminus1)
+}
+
+// addCanaryConfigWhenAvailable waits for the metrics backend to become
+// available before starting analysis for canaryConfig, retrying with
+// exponential backoff. A canary added while the backend is down would
+// otherwise be stranded : the informer's periodic resync only fires
+// UpdateFunc, and updateCanaryConfig only restarts analysis when a
+// spec field that affects it has changed. The wait is cancelled if the
+// controller shuts down before the backend recovers.
+func (canaryCfgMgr *canaryConfigMgr) addCanaryConfigWhenAvailable(canaryConfig *crd.CanaryConfig) {
+	key := namespacedNameForCanaryConfig(canaryConfig)
+	backoff := 1 * time.Second
+	maxBackoff := 1 * time.Minute
 
 	for {
+		ctx, cancel := context.WithTimeout(context.Background(), availabilityCheckTimeout)
+		err := canaryCfgMgr.metricProvider.CheckAvailability(ctx)
+		cancel()
+		if err == nil {
+			canaryCfgMgr.addCanaryConfig(canaryConfig)
+			return
+		}
+
+		log.Printf("Metrics backend unreachable, retrying canary %v in %v : %v", key, backoff, err)
+		canaryCfgMgr.recordEvent(canaryConfig, corev1.EventTypeWarning, "MetricsBackendUnavailable",
+			fmt.Sprintf("Metrics backend unreachable, retrying rollout start in %v : %v", backoff, err))
+
 		select {
-		case <- ticker.C:
-			// TODO : comment above deleteCanaryConfig function.
-			// every time we're woken up, we need to check if this canary config is still in the store,
-			// else, close(quit).
+		case <-canaryCfgMgr.rootCtx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (canaryCfgMgr *canaryConfigMgr) addCanaryConfig(canaryConfig *crd.CanaryConfig) {
+	key := namespacedNameForCanaryConfig(canaryConfig)
+	ctx := canaryCfgMgr.startRollout(key)
+
+	canaryCfgMgr.recordInitialWeights(canaryConfig)
 
+	ticker := time.NewTicker(canaryConfig.Spec.WeightIncrementDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
 			// every weightIncrementDuration, check if failureThreshold has reached.
 			// if yes, rollback.
 			// else, increment the weight percentage of funcN and decrement funcN-1 by `weightIncrement`
-			canaryCfgMgr.processCanaryConfig(canaryConfig, quit)
-		case <- quit:
-			ticker.Stop()
+			canaryCfgMgr.processCanaryConfig(ctx, canaryConfig)
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// deleteCanaryConfig stops the analysis goroutine for a canary config that
+// was removed from the store.
+func (canaryCfgMgr *canaryConfigMgr) deleteCanaryConfig(canaryConfig *crd.CanaryConfig) {
+	canaryCfgMgr.stopRollout(canaryConfig)
+}
+
+// updateCanaryConfig restarts the analysis goroutine with a fresh schedule
+// whenever a field that affects analysis changes underneath a running
+// rollout.
+func (canaryCfgMgr *canaryConfigMgr) updateCanaryConfig(oldConfig *crd.CanaryConfig, newConfig *crd.CanaryConfig) {
+	if oldConfig.Spec.WeightIncrement == newConfig.Spec.WeightIncrement &&
+		oldConfig.Spec.WeightIncrementDuration == newConfig.Spec.WeightIncrementDuration &&
+		oldConfig.Spec.FailureThreshold == newConfig.Spec.FailureThreshold &&
+		oldConfig.Spec.FunctionN == newConfig.Spec.FunctionN &&
+		oldConfig.Spec.FunctionNminus1 == newConfig.Spec.FunctionNminus1 {
+		// nothing that affects analysis changed ; leave the running rollout alone.
+		return
+	}
+
+	log.Printf("Analysis-relevant spec changed for canary config %v ; restarting with the new schedule",
+		namespacedNameForCanaryConfig(newConfig))
+	go canaryCfgMgr.addCanaryConfig(newConfig)
+}
+
+func namespacedNameForCanaryConfig(canaryConfig *crd.CanaryConfig) types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: canaryConfig.Metadata.Namespace,
+		Name:      canaryConfig.Metadata.Name,
+	}
+}
+
+// recordInitialWeights snapshots the FunctionN / FunctionN-1 weight split a
+// canary rollout is starting from, so that a function revision change
+// detected mid-rollout can reset the trigger back to this split.
+func (canaryCfgMgr *canaryConfigMgr) recordInitialWeights(canaryConfig *crd.CanaryConfig) {
+	key := namespacedNameForCanaryConfig(canaryConfig)
+
+	t, err := canaryCfgMgr.fissionClient.HTTPTriggers(canaryConfig.Metadata.Namespace).Get(canaryConfig.Spec.Trigger.Name)
+	if err != nil {
+		log.Printf("Error fetching http trigger %v to record initial canary weights : %v", canaryConfig.Spec.Trigger.Name, err)
+		return
+	}
+
+	weights := canaryWeights{
+		functionNWeight:       t.Spec.FunctionReference.FunctionWeights[canaryConfig.Spec.FunctionN],
+		functionNminus1Weight: t.Spec.FunctionReference.FunctionWeights[canaryConfig.Spec.FunctionNminus1],
+	}
+	canaryCfgMgr.revisionTracker.recordInitialWeights(key, weights)
+	canaryCfgMgr.notifier.NotifyStart(canaryConfig.Metadata.Namespace, canaryConfig.Spec.Trigger.Name,
+		canaryConfig.Spec.FunctionN, canaryConfig.Spec.FunctionNminus1, weights)
+}
+
+// checkFunctionRevision detects whether FunctionN has a new revision
+// underneath this rollout, whether FunctionN-1 has been removed (the rollout
+// is effectively already promoted), or whether FunctionN itself has been
+// removed/renamed (the rollout can no longer be analyzed and is aborted).
+func (canaryCfgMgr *canaryConfigMgr) checkFunctionRevision(canaryConfig *crd.CanaryConfig) (restarted bool, aborted bool, promoted bool, err error) {
+	ns := canaryConfig.Metadata.Namespace
+	key := namespacedNameForCanaryConfig(canaryConfig)
+
+	_, err = canaryCfgMgr.fissionClient.Functions(ns).Get(canaryConfig.Spec.FunctionNminus1)
+	if err != nil {
+		if k8sErrs.IsNotFound(err) {
+			return false, false, true, nil
+		}
+		return false, false, false, err
+	}
+
+	fn, err := canaryCfgMgr.fissionClient.Functions(ns).Get(canaryConfig.Spec.FunctionN)
+	if err != nil {
+		if k8sErrs.IsNotFound(err) {
+			return false, true, false, nil
+		}
+		return false, false, false, err
+	}
+
+	hash, err := functionSpecHash(fn)
+	if err != nil {
+		return false, false, false, err
+	}
+
+	if !canaryCfgMgr.revisionTracker.checkAndUpdate(key, hash) {
+		return false, false, false, nil
+	}
+
+	// FunctionN's spec changed mid-rollout : put the trigger back to the
+	// weight split the rollout started from and restart analysis from step
+	// zero, rather than blending failure counts across two function
+	// revisions.
+	initial, ok := canaryCfgMgr.revisionTracker.getInitialWeights(key)
+	if !ok {
+		log.Printf("New function revision detected for canary config %v, but no initial weights were recorded; skipping restart", key)
+		return false, false, false, nil
+	}
+
+	t, err := canaryCfgMgr.fissionClient.HTTPTriggers(ns).Get(canaryConfig.Spec.Trigger.Name)
+	if err != nil {
+		return false, false, false, err
+	}
+
+	functionWeights := t.Spec.FunctionReference.FunctionWeights
+	functionWeights[canaryConfig.Spec.FunctionN] = initial.functionNWeight
+	functionWeights[canaryConfig.Spec.FunctionNminus1] = initial.functionNminus1Weight
+	t.Spec.FunctionReference.FunctionWeights = functionWeights
+
+	_, err = canaryCfgMgr.fissionClient.HTTPTriggers(ns).Update(t)
+	if err != nil {
+		return false, false, false, err
+	}
+
+	canaryWeightGauge.WithLabelValues(ns, canaryConfig.Spec.Trigger.Name, canaryConfig.Spec.FunctionN).Set(float64(initial.functionNWeight))
+	canaryWeightGauge.WithLabelValues(ns, canaryConfig.Spec.Trigger.Name, canaryConfig.Spec.FunctionNminus1).Set(float64(initial.functionNminus1Weight))
+
+	canaryCfgMgr.requestTracker.reset(&canaryConfig.Spec.Trigger)
+	resetRolloutStart(key)
+	log.Printf("New function revision detected for %v of canary config %v ; restarted rollout at %v=%v%%, %v=%v%%",
+		canaryConfig.Spec.FunctionN, key, canaryConfig.Spec.FunctionN, initial.functionNWeight,
+		canaryConfig.Spec.FunctionNminus1, initial.functionNminus1Weight)
+
+	return true, false, false, nil
+}
+
+func (canaryCfgMgr *canaryConfigMgr) processCanaryConfig(ctx context.Context, canaryConfig *crd.CanaryConfig) {
+	key := namespacedNameForCanaryConfig(canaryConfig)
+	ns := canaryConfig.Metadata.Namespace
+	triggerName := canaryConfig.Spec.Trigger.Name
+
+	restarted, aborted, promoted, err := canaryCfgMgr.checkFunctionRevision(canaryConfig)
+	if err != nil {
+		log.Printf("Error checking function revision for canary config %v : %v", key, err)
+	} else if promoted {
+		log.Printf("FunctionN-1 %v for canary config %v no longer exists ; treating rollout as already promoted",
+			canaryConfig.Spec.FunctionNminus1, key)
+		setCanaryPhase(ns, triggerName, phaseSucceeded)
+		observeRolloutDuration(ns, triggerName, key)
+		canaryCfgMgr.notifier.NotifyPromotion(ns, triggerName, canaryConfig.Spec.FunctionN)
+		canaryCfgMgr.stopRollout(canaryConfig)
+		return
+	} else if aborted {
+		log.Printf("FunctionN %v for canary config %v no longer exists ; aborting canary rollout",
+			canaryConfig.Spec.FunctionN, key)
+		canaryCfgMgr.rollback(ctx, canaryConfig)
+		canaryCfgMgr.notifier.NotifyRollback(ns, triggerName, canaryConfig.Spec.FunctionN, canaryConfig.Spec.FunctionNminus1, 0)
+		setCanaryPhase(ns, triggerName, phaseFailed)
+		observeRolloutDuration(ns, triggerName, key)
+		canaryCfgMgr.stopRollout(canaryConfig)
+		return
+	} else if restarted {
+		// analysis restarts from step zero on the next tick, with fresh request counters.
+		return
+	}
 
-func(canaryCfgMgr *canaryConfigMgr) processCanaryConfig(canaryConfig *crd.CanaryConfig, quit chan struct{}) {
-	// TODO : Use prometheus apis to get metrics
 	requestCounter := canaryCfgMgr.requestTracker.get(&canaryConfig.Spec.Trigger)
 
 	if requestCounter == nil || requestCounter.TotalRequests == 0 {
@@ -120,20 +428,28 @@ func(canaryCfgMgr *canaryConfigMgr) processCanaryConfig(canaryConfig *crd.Canary
 		return
 	}
 
-	// TODO : Use prometheus apis to get percentage failures
-	failurePercent := calculatePercentageFailure(requestCounter)
+	metricsCtx, cancel := context.WithTimeout(context.Background(), availabilityCheckTimeout)
+	failurePercent, err := canaryCfgMgr.metricProvider.GetFunctionFailurePercentage(
+		metricsCtx, canaryConfig.Spec.FunctionN, ns, canaryConfig.Spec.WeightIncrementDuration)
+	cancel()
+	if err != nil {
+		log.Printf("Error querying metric provider for failure percentage of canary config %v : %v", key, err)
+		return
+	}
 
 	if failurePercent > canaryConfig.Spec.FailureThreshold {
-		// TODO : Need to decide the behavior or rollback.
-		rollback()
-		close(quit)
+		canaryCfgMgr.rollback(ctx, canaryConfig)
+		canaryCfgMgr.notifier.NotifyRollback(ns, triggerName, canaryConfig.Spec.FunctionN, canaryConfig.Spec.FunctionNminus1, failurePercent)
+		setCanaryPhase(ns, triggerName, phaseRolledback)
+		observeRolloutDuration(ns, triggerName, key)
+		canaryCfgMgr.stopRollout(canaryConfig)
 		return
 	}
 
 	// time to increment the weight of functionN and decrement the weight of functionN-1 by `weightIncrement`
 	t, err := canaryCfgMgr.fissionClient.HTTPTriggers(canaryConfig.Metadata.Namespace).Get(canaryConfig.Spec.Trigger.Name)
 	if err != nil {
-		// TODO if err is NotFound, then close(quit) from this go-routine.
+		// TODO if err is NotFound, then canaryCfgMgr.stopRollout(canaryConfig) from this go-routine.
 
 		// nothing to do, because the trigger object is missing
 		return
@@ -155,10 +471,19 @@ func(canaryCfgMgr *canaryConfigMgr) processCanaryConfig(canaryConfig *crd.Canary
 	// if write is successful, reset the counters so the failure percentage can be calculated for next interval
 	canaryCfgMgr.requestTracker.reset(&canaryConfig.Spec.Trigger)
 
-	// if write was successful and if the functionN has reached 100% and functionN-1 0%, then quit, our job is done.
+	recordRolloutStart(key)
+	canaryWeightGauge.WithLabelValues(ns, triggerName, canaryConfig.Spec.FunctionN).Set(float64(functionWeights[canaryConfig.Spec.FunctionN]))
+	canaryWeightGauge.WithLabelValues(ns, triggerName, canaryConfig.Spec.FunctionNminus1).Set(float64(functionWeights[canaryConfig.Spec.FunctionNminus1]))
+
+	// if write was successful and if the functionN has reached 100% and functionN-1 0%, then we're done.
 	if functionWeights[canaryConfig.Spec.FunctionN] >= 100 {
-		close(quit)
+		setCanaryPhase(ns, triggerName, phaseSucceeded)
+		observeRolloutDuration(ns, triggerName, key)
+		canaryCfgMgr.notifier.NotifyPromotion(ns, triggerName, canaryConfig.Spec.FunctionN)
+		canaryCfgMgr.stopRollout(canaryConfig)
+	} else {
+		setCanaryPhase(ns, triggerName, phaseProgressing)
+		canaryCfgMgr.notifier.NotifyWeightStep(ns, triggerName, canaryConfig.Spec.FunctionN, canaryConfig.Spec.FunctionNminus1,
+			functionWeights[canaryConfig.Spec.FunctionN], functionWeights[canaryConfig.Spec.FunctionNminus1])
 	}
 }
-
-
@@ -0,0 +1,43 @@
+package canaryconfigmgr
+
+import (
+	"fmt"
+	"log"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission/crd"
+)
+
+// recordEvent creates a Kubernetes Event on the given CanaryConfig. CanaryConfig
+// keeps its ObjectMeta in a named Metadata field rather than embedding it, so
+// it doesn't satisfy metav1.Object and a record.EventRecorder can't build a
+// reference to it ; this posts the Event directly through the core client
+// instead.
+func (canaryCfgMgr *canaryConfigMgr) recordEvent(canaryConfig *crd.CanaryConfig, eventType, reason, message string) {
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%v-", canaryConfig.Metadata.Name),
+			Namespace:    canaryConfig.Metadata.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "CanaryConfig",
+			Namespace: canaryConfig.Metadata.Namespace,
+			Name:      canaryConfig.Metadata.Name,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: "canaryConfigMgr"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	_, err := canaryCfgMgr.kubeClient.CoreV1().Events(canaryConfig.Metadata.Namespace).Create(event)
+	if err != nil {
+		log.Printf("Error recording event %v for canary config %v : %v", reason, namespacedNameForCanaryConfig(canaryConfig), err)
+	}
+}
@@ -0,0 +1,22 @@
+package canaryconfigmgr
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// MetricProvider abstracts the metrics backend canaryconfigmgr queries to
+// decide whether a canary rollout should progress or roll back.
+// PrometheusApiClient is the only implementation today ; InfluxDB or Datadog
+// backed providers can be added behind this same interface.
+type MetricProvider interface {
+	// CheckAvailability returns an error if the metrics backend cannot be
+	// reached, so canaryconfigmgr can refuse to start trusting empty query
+	// results as "no failures" during an outage.
+	CheckAvailability(ctx context.Context) error
+
+	// GetFunctionFailurePercentage returns the percentage of requests to
+	// funcName in funcNs that failed over the trailing window.
+	GetFunctionFailurePercentage(ctx context.Context, funcName string, funcNs string, window time.Duration) (float64, error)
+}
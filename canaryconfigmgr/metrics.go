@@ -0,0 +1,145 @@
+package canaryconfigmgr
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fission/fission/crd"
+)
+
+const (
+	phaseProgressing = "progressing"
+	phaseSucceeded   = "succeeded"
+	phaseFailed      = "failed"
+	phaseRolledback  = "rolledback"
+)
+
+var allPhases = []string{phaseProgressing, phaseSucceeded, phaseFailed, phaseRolledback}
+
+var (
+	canaryWeightGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fission_canary_weight",
+		Help: "Current traffic weight of a function in a canary rollout",
+	}, []string{"namespace", "trigger", "function"})
+
+	canaryTotalGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fission_canary_total",
+		Help: "Number of CanaryConfigs currently tracked by canaryconfigmgr",
+	}, []string{"namespace"})
+
+	canaryStatusGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fission_canary_status",
+		Help: "Current phase of a canary rollout ; 1 for the active phase, 0 for the others",
+	}, []string{"namespace", "trigger", "phase"})
+
+	canaryDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "fission_canary_duration_seconds",
+		Help:    "How long a canary rollout took from its first weight bump to reaching 100% or being rolled back",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"namespace", "trigger"})
+
+	canaryInfoGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "fission_canary_info",
+		Help: "Build information of the running canaryconfigmgr, always set to 1",
+	}, []string{"version"})
+)
+
+func init() {
+	prometheus.MustRegister(canaryWeightGauge, canaryTotalGauge, canaryStatusGauge, canaryDurationHistogram, canaryInfoGauge)
+}
+
+// MetricsHandler returns the http.Handler the controller's HTTP server should
+// mount at /metrics so these rollout metrics can be scraped.
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RecordBuildInfo sets fission_canary_info so the metrics scrape identifies
+// which canaryconfigmgr build produced the rollout metrics.
+func RecordBuildInfo(version string) {
+	canaryInfoGauge.Reset()
+	canaryInfoGauge.WithLabelValues(version).Set(1)
+}
+
+// setCanaryPhase marks phase as the active phase for a trigger's rollout and
+// clears every other known phase, since canary_status is one gauge per phase
+// rather than a single enum value.
+func setCanaryPhase(namespace, trigger, phase string) {
+	for _, p := range allPhases {
+		value := 0.0
+		if p == phase {
+			value = 1
+		}
+		canaryStatusGauge.WithLabelValues(namespace, trigger, p).Set(value)
+	}
+}
+
+var (
+	rolloutStartMutex = &sync.Mutex{}
+	rolloutStart      = make(map[types.NamespacedName]time.Time)
+)
+
+// recordRolloutStart notes the time of a rollout's first weight bump, if one
+// hasn't already been recorded for key.
+func recordRolloutStart(key types.NamespacedName) {
+	rolloutStartMutex.Lock()
+	defer rolloutStartMutex.Unlock()
+	if _, ok := rolloutStart[key]; !ok {
+		rolloutStart[key] = time.Now()
+	}
+}
+
+// resetRolloutStart forgets a rollout's recorded start time, so that a
+// restart of its analysis (e.g. a new FunctionN revision detected
+// mid-rollout) makes fission_canary_duration_seconds measure from the
+// restart's first weight bump rather than the original start.
+func resetRolloutStart(key types.NamespacedName) {
+	rolloutStartMutex.Lock()
+	defer rolloutStartMutex.Unlock()
+	delete(rolloutStart, key)
+}
+
+// observeRolloutDuration records how long the rollout tracked by key has been
+// running into fission_canary_duration_seconds, and forgets its start time.
+func observeRolloutDuration(namespace, trigger string, key types.NamespacedName) {
+	rolloutStartMutex.Lock()
+	start, ok := rolloutStart[key]
+	delete(rolloutStart, key)
+	rolloutStartMutex.Unlock()
+
+	if !ok {
+		return
+	}
+	canaryDurationHistogram.WithLabelValues(namespace, trigger).Observe(time.Since(start).Seconds())
+}
+
+// deleteCanaryMetrics removes the canaryWeightGauge and canaryStatusGauge
+// series recorded for a rollout, so a deleted or finished canary doesn't
+// keep exporting stale series indefinitely.
+func deleteCanaryMetrics(namespace, trigger, functionN, functionNminus1 string) {
+	canaryWeightGauge.DeleteLabelValues(namespace, trigger, functionN)
+	canaryWeightGauge.DeleteLabelValues(namespace, trigger, functionNminus1)
+	for _, p := range allPhases {
+		canaryStatusGauge.DeleteLabelValues(namespace, trigger, p)
+	}
+}
+
+// updateCanaryTotalMetrics recomputes fission_canary_total from the current
+// contents of the canary config store.
+func (canaryCfgMgr *canaryConfigMgr) updateCanaryTotalMetrics() {
+	counts := make(map[string]int)
+	for _, obj := range canaryCfgMgr.canaryConfigStore.List() {
+		canaryConfig := obj.(*crd.CanaryConfig)
+		counts[canaryConfig.Metadata.Namespace]++
+	}
+
+	canaryTotalGauge.Reset()
+	for namespace, count := range counts {
+		canaryTotalGauge.WithLabelValues(namespace).Set(float64(count))
+	}
+}
@@ -0,0 +1,126 @@
+package canaryconfigmgr
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Notifier is told about canary rollout lifecycle events, so operators can
+// watch a rollout progress without polling Kubernetes or the metrics
+// backend.
+type Notifier interface {
+	NotifyStart(namespace, trigger, functionN, functionNminus1 string, weights canaryWeights)
+	NotifyWeightStep(namespace, trigger, functionN, functionNminus1 string, functionNWeight, functionNminus1Weight int)
+	NotifyRollback(namespace, trigger, functionN, functionNminus1 string, failurePercent float64)
+	NotifyPromotion(namespace, trigger, functionN string)
+}
+
+// noopNotifier discards every notification. It's the default when no
+// notifier is configured, and is handy for stubbing out notifications in
+// tests.
+type noopNotifier struct{}
+
+func (noopNotifier) NotifyStart(namespace, trigger, functionN, functionNminus1 string, weights canaryWeights) {
+}
+func (noopNotifier) NotifyWeightStep(namespace, trigger, functionN, functionNminus1 string, functionNWeight, functionNminus1Weight int) {
+}
+func (noopNotifier) NotifyRollback(namespace, trigger, functionN, functionNminus1 string, failurePercent float64) {
+}
+func (noopNotifier) NotifyPromotion(namespace, trigger, functionN string) {}
+
+// SlackNotifier posts canary rollout events to a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookUrl string
+	Channel    string
+	httpClient *http.Client
+}
+
+var _ Notifier = &SlackNotifier{}
+
+// NewSlackNotifier creates a Notifier that posts to the given Slack
+// incoming webhook URL, optionally overriding the webhook's default channel.
+func NewSlackNotifier(webhookUrl string, channel string) *SlackNotifier {
+	return &SlackNotifier{
+		WebhookUrl: webhookUrl,
+		Channel:    channel,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// SlackNotifierFlags holds the parsed values of the controller flags that
+// configure canary rollout notifications.
+type SlackNotifierFlags struct {
+	WebhookUrl string
+	Channel    string
+}
+
+// RegisterSlackNotifierFlags registers --slack-webhook-url and
+// --slack-channel on fs. Call NotifierFromFlags with the returned
+// *SlackNotifierFlags after fs.Parse has run to build the Notifier
+// MakeCanaryConfigMgr should be given.
+func RegisterSlackNotifierFlags(fs *flag.FlagSet) *SlackNotifierFlags {
+	flags := &SlackNotifierFlags{}
+	fs.StringVar(&flags.WebhookUrl, "slack-webhook-url", "",
+		"Slack incoming webhook URL to post canary rollout events to ; unset disables Slack notifications")
+	fs.StringVar(&flags.Channel, "slack-channel", "",
+		"Slack channel to post canary rollout events to, overriding the webhook's default channel")
+	return flags
+}
+
+// NotifierFromFlags builds the Notifier MakeCanaryConfigMgr should be given
+// from parsed SlackNotifierFlags. An empty WebhookUrl means Slack
+// notifications weren't configured, so rollout events are silently
+// discarded.
+func NotifierFromFlags(flags *SlackNotifierFlags) Notifier {
+	if flags == nil || len(flags.WebhookUrl) == 0 {
+		return noopNotifier{}
+	}
+	return NewSlackNotifier(flags.WebhookUrl, flags.Channel)
+}
+
+func (s *SlackNotifier) NotifyStart(namespace, trigger, functionN, functionNminus1 string, weights canaryWeights) {
+	s.post(fmt.Sprintf("Canary rollout started for trigger %v/%v : %v=%v%%, %v=%v%%",
+		namespace, trigger, functionN, weights.functionNWeight, functionNminus1, weights.functionNminus1Weight))
+}
+
+func (s *SlackNotifier) NotifyWeightStep(namespace, trigger, functionN, functionNminus1 string, functionNWeight, functionNminus1Weight int) {
+	s.post(fmt.Sprintf("Canary rollout step for trigger %v/%v : %v=%v%%, %v=%v%%",
+		namespace, trigger, functionN, functionNWeight, functionNminus1, functionNminus1Weight))
+}
+
+func (s *SlackNotifier) NotifyRollback(namespace, trigger, functionN, functionNminus1 string, failurePercent float64) {
+	s.post(fmt.Sprintf("Canary rollout for trigger %v/%v rolled back : %v failure rate %.2f%% exceeded threshold",
+		namespace, trigger, functionN, failurePercent))
+}
+
+func (s *SlackNotifier) NotifyPromotion(namespace, trigger, functionN string) {
+	s.post(fmt.Sprintf("Canary rollout for trigger %v/%v promoted %v to 100%%", namespace, trigger, functionN))
+}
+
+func (s *SlackNotifier) post(text string) {
+	payload, err := json.Marshal(map[string]string{
+		"channel": s.Channel,
+		"text":    text,
+	})
+	if err != nil {
+		log.Errorf("Error marshalling slack notification : %v", err)
+		return
+	}
+
+	resp, err := s.httpClient.Post(s.WebhookUrl, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Errorf("Error posting slack notification : %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("Slack webhook returned non-200 status : %v", resp.Status)
+	}
+}
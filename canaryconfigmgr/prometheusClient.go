@@ -3,6 +3,7 @@ package canaryconfigmgr
 import (
 	"fmt"
 	"time"
+
 	"golang.org/x/net/context"
 
 	promApi "github.com/prometheus/client_golang/api/prometheus"
@@ -10,11 +11,15 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// PrometheusApiClient is the default MetricProvider, backed by a Prometheus
+// HTTP API client.
 type PrometheusApiClient struct {
 	client promApi.QueryAPI
 	// Add more stuff later
 }
 
+var _ MetricProvider = &PrometheusApiClient{}
+
 // TODO  prometheusSvc will need to come from helm chart value and passed to controller pod.
 // controllerpod then passes this during canaryConfigMgr create
 func makePrometheusClient(prometheusSvc string) *PrometheusApiClient {
@@ -34,32 +39,42 @@ func makePrometheusClient(prometheusSvc string) *PrometheusApiClient {
 	}
 }
 
-func(promApi *PrometheusApiClient) GetFunctionFailurePercentage(funcName string, funcNs string, timeDuration time.Time) {
-	queryString := fmt.Sprintf("fission_function_errors_total{name=%s,namespace=%s}", funcName, funcNs)
-	val, err := promApi.client.Query(context.Background(), queryString, timeDuration)
+// CheckAvailability runs a trivial instant query against Prometheus to verify
+// it is actually reachable and serving queries, rather than trusting an empty
+// result set from a backend that's down as "nothing failed".
+func (promApi *PrometheusApiClient) CheckAvailability(ctx context.Context) error {
+	_, err := promApi.client.Query(ctx, "up", time.Now())
 	if err != nil {
-		log.Errorf("Error querying prometheus for fission_function_errors_total, err : %v", err)
+		return fmt.Errorf("prometheus availability check failed : %v", err)
 	}
+	return nil
+}
 
-	//jsonData, err := value.Type().MarshalJSON()
-	//if err != nil {
-	//	log.Printf("Error marshalling value into json. err : %v", err)
-	//}
-	//
-	//json.Unmarshal(jsonData, model.Vector)
+// GetFunctionFailurePercentage implements MetricProvider by querying the
+// ratio of fission_function_errors_total to fission_function_calls_total for
+// funcName over the trailing window.
+func (promApi *PrometheusApiClient) GetFunctionFailurePercentage(ctx context.Context, funcName string, funcNs string, window time.Duration) (float64, error) {
+	queryString := fmt.Sprintf(
+		"sum(increase(fission_function_errors_total{name=\"%s\",namespace=\"%s\"}[%s])) / sum(increase(fission_function_calls_total{name=\"%s\",namespace=\"%s\"}[%s])) * 100",
+		funcName, funcNs, window, funcName, funcNs, window)
 
+	val, err := promApi.client.Query(ctx, queryString, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("error querying prometheus for function failure percentage, err : %v", err)
+	}
 
-	switch {
-	case val.Type() == model.ValScalar:
+	switch val.Type() {
+	case model.ValScalar:
 		scalarVal := val.(*model.Scalar)
-		// handle scalar stuff
-	case val.Type() == model.ValVector:
+		return float64(scalarVal.Value), nil
+	case model.ValVector:
 		vectorVal := val.(model.Vector)
-		for _, elem := range vectorVal {
-			log.Printf("labels : %s, Elem value : %v", elem.Metric, elem.Value)
-			//TODO : Calculate here
+		if len(vectorVal) == 0 {
+			// no calls recorded for funcName in this window, so nothing failed either.
+			return 0, nil
 		}
+		return float64(vectorVal[0].Value), nil
 	default:
-		log.Printf("type uncrecognized")
+		return 0, fmt.Errorf("unexpected prometheus result type for function failure percentage query : %v", val.Type())
 	}
-}
\ No newline at end of file
+}
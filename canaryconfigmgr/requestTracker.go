@@ -6,21 +6,21 @@ import (
 	"github.com/fission/fission/pkg/apis/fission.io/v1"
 )
 
-type(
+type (
 	RequestTracker struct {
-		mutex *sync.Mutex
+		mutex   *sync.Mutex
 		Counter map[v1.TriggerReference]*RequestCounter
 	}
 
 	RequestCounter struct {
-		TotalRequests int
+		TotalRequests  int
 		FailedRequests int
 	}
 )
 
 func makeRequestTracker() *RequestTracker {
 	return &RequestTracker{
-		mutex : &sync.Mutex{},
+		mutex:   &sync.Mutex{},
 		Counter: make(map[v1.TriggerReference]*RequestCounter, 0),
 	}
 }
@@ -49,3 +49,13 @@ func (reqTracker *RequestTracker) get(triggerRef *v1.TriggerReference) *RequestC
 
 	return reqTracker.Counter[*triggerRef]
 }
+
+// reset clears the counters recorded for triggerRef, so failure percentage
+// is calculated fresh over the next analysis interval rather than blending
+// in requests counted before a weight change or a restarted rollout.
+func (reqTracker *RequestTracker) reset(triggerRef *v1.TriggerReference) {
+	reqTracker.mutex.Lock()
+	defer reqTracker.mutex.Unlock()
+
+	delete(reqTracker.Counter, *triggerRef)
+}
@@ -0,0 +1,98 @@
+package canaryconfigmgr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fission/fission/crd"
+)
+
+// canaryWeights captures the FunctionN / FunctionN-1 weight split that a
+// canary rollout started from, so a restart can put the HTTPTrigger back
+// exactly where it began.
+type canaryWeights struct {
+	functionNWeight       int
+	functionNminus1Weight int
+}
+
+// revisionTracker remembers, per canary config, the last observed hash of
+// FunctionN's spec and the weight split the rollout started from. This lets
+// processCanaryConfig notice when someone pushes a new function revision in
+// the middle of a rollout, instead of happily averaging failure rates across
+// two different function versions.
+type revisionTracker struct {
+	mutex   *sync.Mutex
+	hashes  map[types.NamespacedName]string
+	initial map[types.NamespacedName]canaryWeights
+}
+
+func makeRevisionTracker() *revisionTracker {
+	return &revisionTracker{
+		mutex:   &sync.Mutex{},
+		hashes:  make(map[types.NamespacedName]string),
+		initial: make(map[types.NamespacedName]canaryWeights),
+	}
+}
+
+func (rt *revisionTracker) recordInitialWeights(key types.NamespacedName, weights canaryWeights) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	rt.initial[key] = weights
+}
+
+func (rt *revisionTracker) getInitialWeights(key types.NamespacedName) (canaryWeights, bool) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	w, ok := rt.initial[key]
+	return w, ok
+}
+
+// checkAndUpdate records the current hash for key and reports whether it
+// differs from the previously observed hash. The first observation for a key
+// is never reported as changed, since there is nothing to restart yet.
+func (rt *revisionTracker) checkAndUpdate(key types.NamespacedName, hash string) (changed bool) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	prev, ok := rt.hashes[key]
+	rt.hashes[key] = hash
+	return ok && prev != hash
+}
+
+func (rt *revisionTracker) delete(key types.NamespacedName) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	delete(rt.hashes, key)
+	delete(rt.initial, key)
+}
+
+// functionSpecHash returns a checksum of the parts of a FunctionSpec that
+// matter for canary analysis. If any of these change, requests served before
+// and after the change aren't comparable, so an in-progress rollout needs to
+// restart analysis rather than keep blending the two.
+func functionSpecHash(fn *crd.Function) (string, error) {
+	relevant := struct {
+		Package     interface{} `json:"package"`
+		Environment interface{} `json:"environment"`
+		Secrets     interface{} `json:"secrets"`
+		ConfigMaps  interface{} `json:"configmaps"`
+		Resources   interface{} `json:"resources"`
+	}{
+		Package:     fn.Spec.Package,
+		Environment: fn.Spec.Environment,
+		Secrets:     fn.Spec.Secrets,
+		ConfigMaps:  fn.Spec.ConfigMaps,
+		Resources:   fn.Spec.Resources,
+	}
+
+	b, err := json.Marshal(relevant)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
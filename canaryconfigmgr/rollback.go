@@ -0,0 +1,122 @@
+package canaryconfigmgr
+
+import (
+	"log"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/fission/fission/crd"
+)
+
+const (
+	// RollbackStrategyImmediate snaps FunctionN to 0% and FunctionN-1 to 100%
+	// in a single write.
+	RollbackStrategyImmediate = "immediate"
+
+	// RollbackStrategyGradual reverses the weight schedule the rollout
+	// progressed with, at the same cadence (WeightIncrement every
+	// WeightIncrementDuration) instead of snapping traffic back in one step.
+	RollbackStrategyGradual = "gradual"
+)
+
+// validRollbackStrategy returns strategy, defaulting to
+// RollbackStrategyImmediate for anything that isn't a strategy this package
+// knows about. CanaryConfigSpec.RollbackStrategy documents an
+// immediate|gradual enum defaulting to "immediate" via kubebuilder markers,
+// but this repo has no codegen pipeline that turns those into an enforced
+// CRD validation schema ; this function is the actual enforcement, so unset
+// values and anything a user writes directly into the spec still land on a
+// safe strategy.
+func validRollbackStrategy(strategy string) string {
+	switch strategy {
+	case RollbackStrategyImmediate, RollbackStrategyGradual:
+		return strategy
+	default:
+		return RollbackStrategyImmediate
+	}
+}
+
+// rollback reverts canaryConfig's HTTPTrigger away from FunctionN, using the
+// strategy configured on the CanaryConfig spec. ctx is the rollout's analysis
+// context ; a gradual rollback is cancelled along with it on controller
+// shutdown or a newer rollout taking over the same trigger.
+func (canaryCfgMgr *canaryConfigMgr) rollback(ctx context.Context, canaryConfig *crd.CanaryConfig) {
+	switch validRollbackStrategy(canaryConfig.Spec.RollbackStrategy) {
+	case RollbackStrategyGradual:
+		canaryCfgMgr.rollbackGradual(ctx, canaryConfig)
+	default:
+		canaryCfgMgr.rollbackImmediate(canaryConfig)
+	}
+}
+
+// rollbackImmediate snaps FunctionN to 0% and FunctionN-1 to 100% in one
+// write to the HTTPTrigger.
+func (canaryCfgMgr *canaryConfigMgr) rollbackImmediate(canaryConfig *crd.CanaryConfig) {
+	ns := canaryConfig.Metadata.Namespace
+	key := namespacedNameForCanaryConfig(canaryConfig)
+
+	t, err := canaryCfgMgr.fissionClient.HTTPTriggers(ns).Get(canaryConfig.Spec.Trigger.Name)
+	if err != nil {
+		log.Printf("Error fetching http trigger %v to roll back canary config %v : %v", canaryConfig.Spec.Trigger.Name, key, err)
+		return
+	}
+
+	functionWeights := t.Spec.FunctionReference.FunctionWeights
+	functionWeights[canaryConfig.Spec.FunctionN] = 0
+	functionWeights[canaryConfig.Spec.FunctionNminus1] = 100
+	t.Spec.FunctionReference.FunctionWeights = functionWeights
+
+	_, err = canaryCfgMgr.fissionClient.HTTPTriggers(ns).Update(t)
+	if err != nil {
+		log.Printf("Error writing immediate rollback weights for canary config %v : %v", key, err)
+	}
+}
+
+// rollbackGradual reverses the canary's weight schedule at the same cadence
+// used to progress it, shifting traffic back to FunctionN-1 one
+// WeightIncrement at a time every WeightIncrementDuration. It selects on
+// ctx.Done() between ticks so a controller shutdown or a newer rollout taking
+// over the same trigger interrupts it, rather than blocking the analysis
+// goroutine until the gradual rollback finishes on its own.
+func (canaryCfgMgr *canaryConfigMgr) rollbackGradual(ctx context.Context, canaryConfig *crd.CanaryConfig) {
+	ns := canaryConfig.Metadata.Namespace
+	key := namespacedNameForCanaryConfig(canaryConfig)
+	ticker := time.NewTicker(canaryConfig.Spec.WeightIncrementDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Gradual rollback for canary config %v cancelled before completing : %v", key, ctx.Err())
+			return
+		case <-ticker.C:
+		}
+
+		t, err := canaryCfgMgr.fissionClient.HTTPTriggers(ns).Get(canaryConfig.Spec.Trigger.Name)
+		if err != nil {
+			log.Printf("Error fetching http trigger %v to gradually roll back canary config %v : %v", canaryConfig.Spec.Trigger.Name, key, err)
+			return
+		}
+
+		functionWeights := t.Spec.FunctionReference.FunctionWeights
+		functionWeights[canaryConfig.Spec.FunctionN] -= canaryConfig.Spec.WeightIncrement
+		functionWeights[canaryConfig.Spec.FunctionNminus1] += canaryConfig.Spec.WeightIncrement
+
+		if functionWeights[canaryConfig.Spec.FunctionN] <= 0 {
+			functionWeights[canaryConfig.Spec.FunctionN] = 0
+			functionWeights[canaryConfig.Spec.FunctionNminus1] = 100
+		}
+		t.Spec.FunctionReference.FunctionWeights = functionWeights
+
+		_, err = canaryCfgMgr.fissionClient.HTTPTriggers(ns).Update(t)
+		if err != nil {
+			log.Printf("Error writing gradual rollback weights for canary config %v : %v", key, err)
+			return
+		}
+
+		if functionWeights[canaryConfig.Spec.FunctionN] <= 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2016 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/fission/fission/pkg/apis/fission.io/v1"
+)
+
+// CanaryConfig describes a canary rollout between two revisions of a
+// function, FunctionN and FunctionNminus1, sharing an HTTPTrigger.
+type CanaryConfig struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        metav1.ObjectMeta `json:"metadata"`
+	Spec            CanaryConfigSpec  `json:"spec"`
+}
+
+// DeepCopyObject implements runtime.Object so CanaryConfig can be handed to
+// a client-go informer.
+func (c *CanaryConfig) DeepCopyObject() runtime.Object {
+	if c == nil {
+		return nil
+	}
+	out := *c
+	return &out
+}
+
+// CanaryConfigSpec is the user-provided configuration of a canary rollout.
+type CanaryConfigSpec struct {
+	// Trigger is the HTTPTrigger whose FunctionReference.FunctionWeights are
+	// adjusted to shift traffic between FunctionN and FunctionNminus1.
+	Trigger v1.TriggerReference `json:"trigger"`
+
+	// FunctionN is the function revision being rolled out.
+	FunctionN string `json:"functionN"`
+
+	// FunctionNminus1 is the function revision being rolled out from.
+	FunctionNminus1 string `json:"functionNminus1"`
+
+	// WeightIncrement is how much traffic weight moves from FunctionNminus1
+	// to FunctionN on every successful analysis interval.
+	WeightIncrement int `json:"weightIncrement"`
+
+	// WeightIncrementDuration is how often the analysis loop checks
+	// FailureThreshold and, if it hasn't been breached, applies the next
+	// WeightIncrement.
+	WeightIncrementDuration time.Duration `json:"weightIncrementDuration"`
+
+	// FailureThreshold is the failure percentage past which the rollout is
+	// rolled back to FunctionNminus1 instead of progressing.
+	FailureThreshold float64 `json:"failureThreshold"`
+
+	// RollbackStrategy chooses how the rollout is reverted once
+	// FailureThreshold is breached (or FunctionN otherwise has to be
+	// abandoned) : "immediate" snaps FunctionNminus1 back to 100% of traffic
+	// in a single write, "gradual" reverses the weight schedule at the same
+	// cadence (WeightIncrement every WeightIncrementDuration) it progressed
+	// with. Defaults to "immediate" ; canaryconfigmgr also treats any
+	// unrecognized value as "immediate", since that's always safe to apply
+	// from any weight split.
+	// +kubebuilder:validation:Enum=immediate;gradual
+	// +kubebuilder:default=immediate
+	RollbackStrategy string `json:"rollbackStrategy,omitempty"`
+}
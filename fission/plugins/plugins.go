@@ -4,13 +4,20 @@ package plugins
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
@@ -21,11 +28,20 @@ const (
 	CmdTimeout           = 5 * time.Second
 	CmdMetadataArgs      = "--plugin"
 	CacheRefreshInterval = 1 * time.Hour
+
+	// DownloadTimeout bounds registry index fetches and plugin binary downloads,
+	// which can take longer than the CmdTimeout budget given to a plugin subprocess.
+	DownloadTimeout = 1 * time.Minute
 )
 
 var (
-	ErrPluginNotFound = errors.New("plugin not found")
-	ErrPluginInvalid  = errors.New("invalid plugin")
+	ErrPluginNotFound          = errors.New("plugin not found")
+	ErrPluginInvalid           = errors.New("invalid plugin")
+	ErrNoRegistriesConfigured  = errors.New("no plugin registries configured")
+	ErrNoTrustedKeysConfigured = errors.New("no trusted signing keys configured")
+	ErrChecksumMismatch        = errors.New("plugin checksum mismatch")
+	ErrSignatureInvalid        = errors.New("plugin signature invalid")
+	ErrVersionIncompatible     = errors.New("plugin is incompatible with this host version")
 )
 
 // Metadata contains the metadata of a plugin.
@@ -39,6 +55,30 @@ type Metadata struct {
 	Usage      string            `json:"usage"`
 	Path       string            `json:"path"`
 	ModifiedAt time.Time         `json:"modifiedAt"`
+
+	// Sha256 and Signature record the provenance of a plugin installed via
+	// Manager.Install : the checksum and detached signature that were
+	// verified against a registry entry at install time. Empty for plugins
+	// Manager merely found on PATH.
+	Sha256    string `json:"sha256,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// RegistryIndexEntry describes a single plugin's distribution metadata, as
+// published in a registry's index file.
+type RegistryIndexEntry struct {
+	Name      string            `json:"name"`
+	Version   string            `json:"version"`
+	Url       string            `json:"url"`
+	Sha256    string            `json:"sha256"`
+	Signature string            `json:"signature"` // base64-encoded detached Ed25519 signature over the binary at Url
+	Requires  map[string]string `json:"requires"`  // e.g. {"fission-cli": ">=1.2.0"}
+}
+
+// registryIndex is the shape of a registry's index file : a flat list of
+// every plugin it distributes.
+type registryIndex struct {
+	Plugins []RegistryIndexEntry `json:"plugins"`
 }
 
 var DefaultManager = &Manager{
@@ -76,6 +116,23 @@ type Manager struct {
 	Registries []string
 	CachePath  string // Empty means: do not cache
 	cache      map[string]*Metadata
+
+	// PluginDir is where Install downloads plugin binaries to, and is
+	// searched in addition to PATH. Defaults to an OS-appropriate directory
+	// under the user's home if empty.
+	PluginDir string
+
+	// HostVersion is the current host binary's version, checked against a
+	// registry entry's Requires before installing.
+	HostVersion string
+
+	// TrustedKeys are the Ed25519 public keys a plugin's signature must
+	// verify against for Install to accept it.
+	TrustedKeys []ed25519.PublicKey
+
+	// VerifyOnExec, if true, makes Exec re-verify an installed plugin's
+	// checksum against the value recorded at install time before running it.
+	VerifyOnExec bool
 }
 
 // Find searches the machine for the given plugin, returning the metadata of the plugin.
@@ -99,6 +156,13 @@ func (mgr *Manager) Find(pluginName string) (*Metadata, error) {
 // All input and output is redirected to stdin, stdout, and stderr.
 func (mgr *Manager) Exec(pluginMetadata *Metadata, args []string) error {
 	// TODO remove from cache if command is in cache and could not be found!
+	if mgr.VerifyOnExec {
+		err := mgr.verifyInstalledBinary(pluginMetadata)
+		if err != nil {
+			return err
+		}
+	}
+
 	cmd := exec.Command(pluginMetadata.Path, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -106,11 +170,13 @@ func (mgr *Manager) Exec(pluginMetadata *Metadata, args []string) error {
 	return cmd.Run()
 }
 
-// FindAll searches the machine for all plugins currently present.
+// FindAll searches the machine for all plugins currently present, on PATH
+// and in the manager's own install directory.
 func (mgr *Manager) FindAll() map[string]*Metadata {
 	plugins := map[string]*Metadata{}
 
 	dirs := strings.Split(os.Getenv("PATH"), ":")
+	dirs = append(dirs, mgr.pluginInstallDir())
 	for _, dir := range dirs {
 		fs, err := ioutil.ReadDir(dir)
 		if err != nil {
@@ -140,17 +206,123 @@ func (mgr *Manager) FindAll() map[string]*Metadata {
 	return plugins
 }
 
+// Install resolves pluginName against each configured registry in order,
+// downloads the first match, verifies its checksum and signature against
+// TrustedKeys, and installs it into PluginDir.
+func (mgr *Manager) Install(pluginName string) error {
+	entry, err := mgr.resolveFromRegistries(pluginName)
+	if err != nil {
+		return err
+	}
+
+	err = mgr.checkRequires(entry.Requires)
+	if err != nil {
+		return err
+	}
+
+	binary, err := downloadPluginBinary(entry.Url)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256Hex(binary)
+	if !strings.EqualFold(sum, entry.Sha256) {
+		return fmt.Errorf("%w: got %v, registry says %v", ErrChecksumMismatch, sum, entry.Sha256)
+	}
+
+	err = mgr.verifySignature(binary, entry.Signature)
+	if err != nil {
+		return err
+	}
+
+	dir := mgr.pluginInstallDir()
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("creating plugin directory %v: %v", dir, err)
+	}
+
+	destPath := filepath.Join(dir, mgr.binaryNameForPlugin(pluginName))
+	err = ioutil.WriteFile(destPath, binary, 0755)
+	if err != nil {
+		return fmt.Errorf("writing plugin binary to %v: %v", destPath, err)
+	}
+
+	md, err := mgr.fetchPluginMetadata(destPath)
+	if err != nil {
+		return err
+	}
+	md.Version = entry.Version
+	md.Url = entry.Url
+	md.Sha256 = sum
+	md.Signature = entry.Signature
+
+	if mgr.useCache() {
+		err = mgr.writeCache(md)
+		if err != nil {
+			logrus.Debugf("Failed to cache plugin metadata for %v: %v", pluginName, err)
+		}
+	}
+
+	return nil
+}
+
+// Update re-resolves pluginName against the configured registries and
+// reinstalls it, overwriting whatever is currently installed.
+func (mgr *Manager) Update(pluginName string) error {
+	return mgr.Install(pluginName)
+}
+
+// Uninstall removes a plugin binary previously installed by Install, along
+// with its cached metadata. Plugins the user placed on PATH themselves are
+// left untouched, since Manager doesn't own that location.
+func (mgr *Manager) Uninstall(pluginName string) error {
+	destPath := filepath.Join(mgr.pluginInstallDir(), mgr.binaryNameForPlugin(pluginName))
+
+	_, err := os.Stat(destPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrPluginNotFound
+		}
+		return err
+	}
+
+	err = os.Remove(destPath)
+	if err != nil {
+		return err
+	}
+
+	if mgr.useCache() {
+		cached, err := mgr.readCache()
+		if err != nil {
+			logrus.Debugf("Failed to read cache while uninstalling %v: %v", pluginName, err)
+			return nil
+		}
+		delete(cached, pluginName)
+		err = mgr.writeCacheAll(cached)
+		if err != nil {
+			logrus.Debugf("Failed to update cache while uninstalling %v: %v", pluginName, err)
+		}
+	}
+
+	return nil
+}
+
 func (mgr *Manager) findPluginPath(pluginName string) (path string, err error) {
 	binaryName := mgr.binaryNameForPlugin(pluginName)
 	path, err = exec.LookPath(binaryName)
-	if err != nil {
-		logrus.Debugf("Plugin not found on PATH: %v", err)
+	if err == nil && len(path) > 0 {
+		return path, nil
 	}
+	logrus.Debugf("Plugin not found on PATH: %v", err)
 
-	if len(path) == 0 {
-		return "", ErrPluginNotFound
+	// not on PATH ; fall back to the manager's own install directory.
+	candidate := filepath.Join(mgr.pluginInstallDir(), binaryName)
+	d, statErr := os.Stat(candidate)
+	if statErr == nil && d.Mode()&0111 != 0 {
+		return candidate, nil
 	}
-	return path, nil
+
+	return "", ErrPluginNotFound
 }
 
 func (mgr *Manager) fetchPluginMetadata(pluginPath string) (*Metadata, error) {
@@ -201,6 +373,182 @@ func (mgr *Manager) fetchPluginMetadata(pluginPath string) (*Metadata, error) {
 	return md, nil
 }
 
+// resolveFromRegistries looks up pluginName in each configured registry in
+// order, returning the first match.
+func (mgr *Manager) resolveFromRegistries(pluginName string) (*RegistryIndexEntry, error) {
+	if len(mgr.Registries) == 0 {
+		return nil, ErrNoRegistriesConfigured
+	}
+
+	var lastErr error
+	for _, registryUrl := range mgr.Registries {
+		entry, err := fetchRegistryEntry(registryUrl, pluginName)
+		if err != nil {
+			logrus.Debugf("Error resolving %v against registry %v: %v", pluginName, registryUrl, err)
+			lastErr = err
+			continue
+		}
+		return entry, nil
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPluginNotFound, lastErr)
+	}
+	return nil, ErrPluginNotFound
+}
+
+// fetchRegistryEntry downloads and parses a registry's index file, looking
+// for pluginName among the plugins it lists.
+func fetchRegistryEntry(registryUrl string, pluginName string) (*RegistryIndexEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DownloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, registryUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry %v returned status %v", registryUrl, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var index registryIndex
+	err = json.Unmarshal(body, &index)
+	if err != nil {
+		return nil, fmt.Errorf("parsing registry index from %v: %v", registryUrl, err)
+	}
+
+	for i := range index.Plugins {
+		if index.Plugins[i].Name == pluginName {
+			return &index.Plugins[i], nil
+		}
+	}
+
+	return nil, ErrPluginNotFound
+}
+
+// downloadPluginBinary fetches a plugin binary from a registry entry's Url.
+func downloadPluginBinary(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DownloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading plugin binary from %v: status %v", url, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// checkRequires refuses installation if the registry entry declares a
+// fission-cli version requirement this host binary doesn't satisfy.
+func (mgr *Manager) checkRequires(requires map[string]string) error {
+	constraint, ok := requires["fission-cli"]
+	if !ok || len(mgr.HostVersion) == 0 {
+		return nil
+	}
+
+	compatible, err := versionSatisfies(mgr.HostVersion, constraint)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVersionIncompatible, err)
+	}
+	if !compatible {
+		return fmt.Errorf("%w: host version %v does not satisfy %v", ErrVersionIncompatible, mgr.HostVersion, constraint)
+	}
+	return nil
+}
+
+// verifySignature checks a plugin binary's detached, base64-encoded Ed25519
+// signature against every configured trusted key, accepting if any one of
+// them verifies it.
+func (mgr *Manager) verifySignature(binary []byte, signatureB64 string) error {
+	if len(mgr.TrustedKeys) == 0 {
+		return ErrNoTrustedKeysConfigured
+	}
+	if len(signatureB64) == 0 {
+		return fmt.Errorf("%w: registry entry has no signature", ErrSignatureInvalid)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(signatureB64)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+
+	for _, key := range mgr.TrustedKeys {
+		if ed25519.Verify(key, binary, signature) {
+			return nil
+		}
+	}
+	return ErrSignatureInvalid
+}
+
+// verifyInstalledBinary recomputes pluginMetadata.Path's checksum and
+// compares it against the one recorded when Install verified it, to catch
+// tampering between install and exec. Plugins with no recorded checksum
+// (found on PATH rather than installed via Install) are left unverified.
+func (mgr *Manager) verifyInstalledBinary(pluginMetadata *Metadata) error {
+	if len(pluginMetadata.Sha256) == 0 {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(pluginMetadata.Path)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256Hex(data)
+	if !strings.EqualFold(sum, pluginMetadata.Sha256) {
+		return fmt.Errorf("%w: %v no longer matches the checksum recorded at install time", ErrChecksumMismatch, pluginMetadata.Name)
+	}
+	return nil
+}
+
+// pluginInstallDir returns the directory Install downloads plugin binaries
+// into, defaulting to an OS-appropriate location under the user's home.
+func (mgr *Manager) pluginInstallDir() string {
+	if len(mgr.PluginDir) > 0 {
+		return mgr.PluginDir
+	}
+
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("LOCALAPPDATA"); len(appData) > 0 {
+			return filepath.Join(appData, "fission", "plugins")
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "fission-plugins")
+	}
+	return filepath.Join(home, ".fission", "plugins")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func (mgr *Manager) useCache() bool {
 	return len(mgr.CachePath) > 0
 }
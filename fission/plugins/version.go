@@ -0,0 +1,89 @@
+package plugins
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// versionSatisfies reports whether version satisfies constraint, a string of
+// the form "<op><version>" where op is one of >=, <=, >, <, =, == (>= is
+// assumed if no op is given). Versions are compared component-wise as
+// dot-separated integers ; a missing trailing component is treated as 0.
+func versionSatisfies(version string, constraint string) (bool, error) {
+	op, constraintVersion := splitConstraint(constraint)
+
+	cmp, err := compareVersions(version, constraintVersion)
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case ">=":
+		return cmp >= 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "=", "==":
+		return cmp == 0, nil
+	default:
+		return false, fmt.Errorf("unrecognized version constraint operator %q", op)
+	}
+}
+
+func splitConstraint(constraint string) (op string, version string) {
+	constraint = strings.TrimSpace(constraint)
+	for _, candidate := range []string{">=", "<=", "==", ">", "<", "="} {
+		if strings.HasPrefix(constraint, candidate) {
+			return candidate, strings.TrimSpace(strings.TrimPrefix(constraint, candidate))
+		}
+	}
+	return ">=", constraint
+}
+
+// compareVersions returns -1, 0, or 1 as a is less than, equal to, or
+// greater than b.
+func compareVersions(a string, b string) (int, error) {
+	aParts, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bParts, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var av, bv int
+		if i < len(aParts) {
+			av = aParts[i]
+		}
+		if i < len(bParts) {
+			bv = bParts[i]
+		}
+		if av != bv {
+			if av < bv {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func parseVersion(version string) ([]int, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	fields := strings.Split(version, ".")
+	parts := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q: %v", version, err)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}